@@ -0,0 +1,270 @@
+// Package api exposes a read-only HTTP+JSON-RPC view of a
+// bios.Network's peer graph, for operators and dashboards that want
+// more than the stdout-only PrintOrderedPeers() table during a launch.
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	bios "github.com/liuchengxu/eos-bios"
+)
+
+// Server is a small HTTP+JSON-RPC daemon sitting in front of a single
+// *bios.Network. It re-uses the Network singleton passed in at
+// construction rather than owning its own copy.
+type Server struct {
+	net        *bios.Network
+	namespaces map[string]bool
+
+	events *eventBroker
+}
+
+// NewServer builds a Server exposing the given namespaces (a subset
+// of "peers", "consensus", "cache", "beacon") over net.
+func NewServer(net *bios.Network, namespaces []string) *Server {
+	enabled := map[string]bool{}
+	for _, ns := range namespaces {
+		enabled[ns] = true
+	}
+
+	return &Server{
+		net:        net,
+		namespaces: enabled,
+		events:     newEventBroker(),
+	}
+}
+
+// NotifyGraphUpdated broadcasts a "graph_updated" SSE event to every
+// /events subscriber. Wired up as bios.Network's OnGraphUpdated
+// callback (see fx/module.go) so it fires after every successful graph
+// update (a failed one sends nothing), whether from the background refresh
+// ticker or an explicit graph.refresh call.
+func (s *Server) NotifyGraphUpdated() {
+	s.events.broadcast("graph_updated")
+}
+
+// ListenAndServe starts the HTTP server on addr. It blocks, same as
+// http.ListenAndServe.
+func (s *Server) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rpc", s.handleRPC)
+	mux.HandleFunc("/events", s.events.handleSSE)
+
+	fmt.Printf("admin API listening on %s (namespaces: %v)\n", addr, s.enabledNamespaceNames())
+
+	return http.ListenAndServe(addr, mux)
+}
+
+func (s *Server) enabledNamespaceNames() []string {
+	var out []string
+	for ns := range s.namespaces {
+		out = append(out, ns)
+	}
+	return out
+}
+
+type rpcRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request) {
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON-RPC request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.dispatch(req.Method, req.Params)
+
+	resp := rpcResponse{ID: req.ID}
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Result = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) namespaceAllowed(ns string) error {
+	if !s.namespaces[ns] {
+		return fmt.Errorf("namespace %q not enabled (see --http.api)", ns)
+	}
+	return nil
+}
+
+// dispatch maps a JSON-RPC method name to a handler, enforcing that
+// its namespace was enabled via --http.api.
+func (s *Server) dispatch(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "peers.ordered":
+		if err := s.namespaceAllowed("peers"); err != nil {
+			return nil, err
+		}
+		return s.net.OrderedPeers(), nil
+
+	case "peers.byAccount":
+		if err := s.namespaceAllowed("peers"); err != nil {
+			return nil, err
+		}
+		var args struct {
+			Account string `json:"account"`
+		}
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("decoding params: %s", err)
+		}
+		for _, peer := range s.net.OrderedPeers() {
+			if peer.AccountName() == args.Account {
+				return peer, nil
+			}
+		}
+		return nil, fmt.Errorf("no peer found for account %q", args.Account)
+
+	case "consensus.report":
+		if err := s.namespaceAllowed("consensus"); err != nil {
+			return nil, err
+		}
+		return s.net.ConsensusReport(), nil
+
+	case "consensus.launchData":
+		if err := s.namespaceAllowed("consensus"); err != nil {
+			return nil, err
+		}
+		return s.net.ConsensusLaunchData()
+
+	case "cache.stat":
+		if err := s.namespaceAllowed("cache"); err != nil {
+			return nil, err
+		}
+		var args struct {
+			Ref string `json:"ref"`
+		}
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("decoding params: %s", err)
+		}
+		return map[string]string{"path": s.net.FileNameFromCache(bios.IPFSRef(args.Ref))}, nil
+
+	case "cache.get":
+		if err := s.namespaceAllowed("cache"); err != nil {
+			return nil, err
+		}
+		var args struct {
+			Ref string `json:"ref"`
+		}
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, fmt.Errorf("decoding params: %s", err)
+		}
+
+		// Copy from ReaderFromCache through a base64 encoder: a plain
+		// string would mangle binary artifacts, since json.Marshal
+		// replaces invalid UTF-8 with U+FFFD. The whole encoded
+		// artifact is held in memory and returned in the single
+		// JSON-RPC response; it is not streamed to the client.
+		reader, err := s.net.ReaderFromCache(bios.IPFSRef(args.Ref))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		var encoded bytes.Buffer
+		enc := base64.NewEncoder(base64.StdEncoding, &encoded)
+		if _, err := io.Copy(enc, reader); err != nil {
+			return nil, fmt.Errorf("streaming %q from cache: %s", args.Ref, err)
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+
+		return map[string]string{"base64": encoded.String()}, nil
+
+	case "beacon.entry":
+		if err := s.namespaceAllowed("beacon"); err != nil {
+			return nil, err
+		}
+		return s.net.BeaconEntry()
+
+	case "graph.refresh":
+		if err := s.namespaceAllowed("peers"); err != nil {
+			return nil, err
+		}
+		if err := s.net.ForceUpdateGraph(); err != nil {
+			return nil, err
+		}
+		// ForceUpdateGraph already triggers net.OnGraphUpdated (wired to
+		// NotifyGraphUpdated in fx/module.go) on success.
+		return true, nil
+
+	default:
+		return nil, fmt.Errorf("unknown method %q", method)
+	}
+}
+
+// eventBroker fans out Server-Sent Events to every connected
+// subscriber whenever the graph changes.
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan string]bool
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: map[chan string]bool{}}
+}
+
+func (b *eventBroker) broadcast(event string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (b *eventBroker) handleSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := make(chan string, 8)
+	b.mu.Lock()
+	b.subscribers[ch] = true
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case event := <-ch:
+			fmt.Fprintf(w, "data: %s\n\n", event)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package bios
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	drandclient "github.com/drand/drand/client"
+	drandhttp "github.com/drand/drand/client/http"
+)
+
+// BeaconConfig is the chunk of a Discovery that lets a peer declare
+// which drand beacon it intends to use to seed the chain ID, and
+// either a specific round or a target UTC time to resolve one from.
+// It is agreed upon by weighted supermajority as the "beacon_config"
+// consensus field (see consensusFieldsForPeer); verifyGraph() does not
+// check it, and peers outside the winning supermajority may declare a
+// different one.
+type BeaconConfig struct {
+	ChainHash  string    `json:"chain_hash" yaml:"chain_hash"`
+	GroupKey   string    `json:"group_key" yaml:"group_key"`
+	Endpoint   string    `json:"endpoint" yaml:"endpoint"`
+	Round      uint64    `json:"round,omitempty" yaml:"round,omitempty"`
+	TargetTime time.Time `json:"target_time,omitempty" yaml:"target_time,omitempty"`
+}
+
+// BeaconEntry is the verified drand round we ended up anchoring the
+// chain ID to, cached on disk so repeated runs are deterministic.
+type BeaconEntry struct {
+	Round      uint64 `json:"round"`
+	Randomness string `json:"randomness"`
+}
+
+func beaconCacheRef(cfg *BeaconConfig) string {
+	return fmt.Sprintf("beacon-%s-%d-%d", cfg.ChainHash, cfg.Round, cfg.TargetTime.Unix())
+}
+
+// encodeBeaconConfig produces the canonical string a peer's
+// BeaconConfig votes with in consensusFieldsForPeer: a nil config
+// (peer didn't declare one) encodes to the empty string, which is a
+// legitimate candidate value of its own.
+func encodeBeaconConfig(cfg *BeaconConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s|%s|%s|%d|%s", cfg.ChainHash, cfg.GroupKey, cfg.Endpoint, cfg.Round, cfg.TargetTime.UTC().Format(time.RFC3339))
+}
+
+// decodeBeaconConfig parses a value produced by encodeBeaconConfig
+// back into a BeaconConfig, so ChainID() can act on the
+// consensus-winning one.
+func decodeBeaconConfig(value string) (*BeaconConfig, error) {
+	if value == "" {
+		return nil, fmt.Errorf("no peer declared a beacon_config")
+	}
+
+	parts := strings.SplitN(value, "|", 5)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("malformed beacon_config value %q", value)
+	}
+
+	round, err := strconv.ParseUint(parts[3], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing round: %s", err)
+	}
+
+	targetTime, err := time.Parse(time.RFC3339, parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("parsing target_time: %s", err)
+	}
+
+	return &BeaconConfig{
+		ChainHash:  parts[0],
+		GroupKey:   parts[1],
+		Endpoint:   parts[2],
+		Round:      round,
+		TargetTime: targetTime,
+	}, nil
+}
+
+// BeaconEntry resolves the consensus-agreed BeaconConfig and returns
+// the verified drand round it seeds the chain ID with, for the admin
+// API's "beacon" namespace.
+func (c *Network) BeaconEntry() (*BeaconEntry, error) {
+	cfg, err := c.consensusBeaconConfig()
+	if err != nil {
+		return nil, err
+	}
+	return c.fetchBeacon(cfg)
+}
+
+// fetchBeacon resolves and verifies the drand round described by cfg,
+// caching the result next to the IPFS cache so that repeated
+// ChainID()/BeaconEntry() calls against the same BeaconConfig are
+// deterministic even if the drand network later becomes unreachable.
+// UpdateGraph() does not fetch or cache the beacon.
+func (c *Network) fetchBeacon(cfg *BeaconConfig) (*BeaconEntry, error) {
+	if cached, err := c.ReadFromCache(beaconCacheRef(cfg)); err == nil {
+		var entry BeaconEntry
+		if err := json.Unmarshal(cached, &entry); err == nil {
+			return &entry, nil
+		}
+	}
+
+	groupKey, err := hex.DecodeString(cfg.GroupKey)
+	if err != nil {
+		return nil, fmt.Errorf("decoding beacon group key: %s", err)
+	}
+
+	httpClient, err := drandhttp.New(cfg.Endpoint, groupKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dialing drand endpoint %q: %s", cfg.Endpoint, err)
+	}
+
+	cl, err := drandclient.New(drandclient.WithChainHash([]byte(cfg.ChainHash)), drandclient.From(httpClient))
+	if err != nil {
+		return nil, fmt.Errorf("building drand client: %s", err)
+	}
+
+	round := cfg.Round
+	if round == 0 {
+		if cfg.TargetTime.IsZero() {
+			return nil, fmt.Errorf("beacon config needs either round or target_time")
+		}
+		round = cl.RoundAt(cfg.TargetTime)
+	}
+
+	res, err := cl.Get(context.Background(), round)
+	if err != nil {
+		return nil, fmt.Errorf("fetching drand round %d: %s", round, err)
+	}
+
+	entry := &BeaconEntry{
+		Round:      res.Round(),
+		Randomness: hex.EncodeToString(res.Randomness()),
+	}
+
+	raw, err := json.Marshal(entry)
+	if err == nil {
+		_ = c.writeToCache(beaconCacheRef(cfg), raw)
+	}
+
+	return entry, nil
+}
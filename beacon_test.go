@@ -0,0 +1,41 @@
+package bios
+
+import "testing"
+
+func TestEncodeDecodeBeaconConfigRoundTrip(t *testing.T) {
+	cfg := &BeaconConfig{
+		ChainHash: "abcd1234",
+		GroupKey:  "deadbeef",
+		Endpoint:  "https://drand.example.org",
+		Round:     42,
+	}
+
+	decoded, err := decodeBeaconConfig(encodeBeaconConfig(cfg))
+	if err != nil {
+		t.Fatalf("decoding: %s", err)
+	}
+
+	if decoded.ChainHash != cfg.ChainHash || decoded.GroupKey != cfg.GroupKey ||
+		decoded.Endpoint != cfg.Endpoint || decoded.Round != cfg.Round ||
+		!decoded.TargetTime.Equal(cfg.TargetTime) {
+		t.Fatalf("round-tripped config %+v, want %+v", decoded, cfg)
+	}
+}
+
+func TestEncodeBeaconConfigNil(t *testing.T) {
+	if encoded := encodeBeaconConfig(nil); encoded != "" {
+		t.Fatalf("expected a nil BeaconConfig to encode to the empty string, got %q", encoded)
+	}
+}
+
+func TestDecodeBeaconConfigEmptyValue(t *testing.T) {
+	if _, err := decodeBeaconConfig(""); err == nil {
+		t.Fatalf("expected decoding the empty string (no peer declared a beacon_config) to error")
+	}
+}
+
+func TestDecodeBeaconConfigMalformed(t *testing.T) {
+	if _, err := decodeBeaconConfig("not|enough|parts"); err == nil {
+		t.Fatalf("expected decoding a malformed value to error")
+	}
+}
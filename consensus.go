@@ -0,0 +1,284 @@
+package bios
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+func parseConsensusTimestamp(value string) (time.Time, error) {
+	return time.Parse("2006-01-02T15:04:05Z", value)
+}
+
+// consensusField is one individually-agreed-upon piece of the launch:
+// a single hash, timestamp, or set that every top-weighted peer votes
+// on by proposing a value in their own Discovery.
+type consensusField struct {
+	Name  string
+	Value string
+	Peer  *Peer
+}
+
+// FieldTally is the per-value breakdown of a single consensusField:
+// how much TotalWeight backs each distinct value proposed for it.
+type FieldTally struct {
+	Field    string
+	Winner   string
+	Weight   float64
+	Agreed   bool
+	Proposed map[string]float64
+}
+
+// ConsensusReport is the full per-field breakdown produced by
+// ReachedConsensus()/ConsensusLaunchData(), so operators can see
+// exactly which BPs disagree on which hash instead of just a
+// yes/no answer.
+type ConsensusReport struct {
+	Threshold float64
+	Fields    []*FieldTally
+}
+
+// Agreed reports whether every field in the report met the
+// supermajority threshold.
+func (r *ConsensusReport) Agreed() bool {
+	for _, f := range r.Fields {
+		if !f.Agreed {
+			return false
+		}
+	}
+	return true
+}
+
+// consensusThreshold returns the weight a value must clear to be
+// declared agreed: 2/3 of the summed TotalWeight of the top 21 peers
+// (the active block producer set), a 2f+1-style supermajority.
+func (c *Network) consensusThreshold() float64 {
+	var sum float64
+	top := c.orderedPeers
+	if len(top) > 21 {
+		top = top[:21]
+	}
+	for _, peer := range top {
+		sum += peer.TotalWeight
+	}
+	return (2.0 / 3.0) * sum
+}
+
+// consensusFieldsForPeer extracts every individually-votable launch
+// artifact out of a peer's LaunchData.
+func consensusFieldsForPeer(peer *Peer) []consensusField {
+	launchData := peer.Discovery.LaunchData
+
+	fields := []consensusField{
+		{Name: "boot_sequence", Value: string(launchData.BootSequence), Peer: peer},
+		{Name: "snapshot", Value: string(launchData.Snapshot), Peer: peer},
+		{Name: "genesis_timestamp", Value: launchData.GenesisTimestamp.UTC().Format("2006-01-02T15:04:05Z"), Peer: peer},
+		{Name: "initial_producers", Value: strings.Join(sortedCopy(launchData.InitialProducers), ","), Peer: peer},
+		{Name: "beacon_config", Value: encodeBeaconConfig(peer.Discovery.BeaconConfig), Peer: peer},
+	}
+
+	contractNames := make([]string, 0, len(launchData.Contracts))
+	for name := range launchData.Contracts {
+		contractNames = append(contractNames, name)
+	}
+	sort.Strings(contractNames)
+
+	for _, name := range contractNames {
+		contract := launchData.Contracts[name]
+		fields = append(fields,
+			consensusField{Name: fmt.Sprintf("contract:%s:abi", name), Value: string(contract.ABI), Peer: peer},
+			consensusField{Name: fmt.Sprintf("contract:%s:code", name), Value: string(contract.Code), Peer: peer},
+		)
+	}
+
+	return fields
+}
+
+func sortedCopy(in []string) []string {
+	out := make([]string, len(in))
+	copy(out, in)
+	sort.Strings(out)
+	return out
+}
+
+// buildConsensusReport tallies, for every consensus field, the
+// TotalWeight of peers (among the top 21) who proposed each distinct
+// value, and determines which value (if any) clears the
+// supermajority threshold.
+func (c *Network) buildConsensusReport() *ConsensusReport {
+	threshold := c.consensusThreshold()
+
+	top := c.orderedPeers
+	if len(top) > 21 {
+		top = top[:21]
+	}
+
+	tallies := map[string]map[string]float64{}
+	fieldOrder := []string{}
+
+	for _, peer := range top {
+		for _, field := range consensusFieldsForPeer(peer) {
+			if _, ok := tallies[field.Name]; !ok {
+				tallies[field.Name] = map[string]float64{}
+				fieldOrder = append(fieldOrder, field.Name)
+			}
+			tallies[field.Name][field.Value] += peer.TotalWeight
+		}
+	}
+
+	sort.Strings(fieldOrder)
+
+	report := &ConsensusReport{Threshold: threshold}
+	for _, name := range fieldOrder {
+		proposed := tallies[name]
+		winner, winnerWeight := pickWinner(proposed)
+
+		report.Fields = append(report.Fields, &FieldTally{
+			Field:    name,
+			Winner:   winner,
+			Weight:   winnerWeight,
+			Agreed:   fieldAgreed(threshold, winnerWeight, proposed, len(top)),
+			Proposed: proposed,
+		})
+	}
+
+	return report
+}
+
+// fieldAgreed decides whether a field's winning value clears
+// consensus. The normal case is a plain supermajority-of-weight
+// check, but a brand-new network (or any small test setup) starts
+// with every peer's TotalWeight at zero, which drives threshold to
+// zero too — so "winnerWeight > threshold" would read 0 > 0 and never
+// pass, making launch permanently impossible even when every peer
+// unanimously proposes the same value. When there's no weight in play
+// yet, fall back to requiring unanimity (exactly one distinct value
+// proposed) instead of a weight comparison that can never succeed.
+func fieldAgreed(threshold, winnerWeight float64, proposed map[string]float64, numPeers int) bool {
+	if threshold > 0 {
+		return winnerWeight > threshold
+	}
+	return numPeers > 0 && len(proposed) == 1
+}
+
+// pickWinner returns the value with the highest tallied weight,
+// breaking ties deterministically by taking the lexicographically
+// smallest candidate among those tied for the max. Iterating a Go map
+// directly (as a naive "track the running max" loop would) makes the
+// winner depend on map iteration order, so two nodes running the same
+// algorithm over the same tallies could disagree on which value won.
+func pickWinner(proposed map[string]float64) (string, float64) {
+	values := make([]string, 0, len(proposed))
+	for value := range proposed {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	var winner string
+	var winnerWeight float64
+	for i, value := range values {
+		if i == 0 || proposed[value] > winnerWeight {
+			winner = value
+			winnerWeight = proposed[value]
+		}
+	}
+
+	return winner, winnerWeight
+}
+
+// consensusBeaconConfig returns the BeaconConfig that won the
+// "beacon_config" consensus field by weighted supermajority, the same
+// way every other launch artifact is agreed upon, rather than a
+// separate all-or-nothing check across every discovered peer.
+func (c *Network) consensusBeaconConfig() (*BeaconConfig, error) {
+	report := c.buildConsensusReport()
+
+	for _, field := range report.Fields {
+		if field.Field != "beacon_config" {
+			continue
+		}
+		if !field.Agreed {
+			return nil, fmt.Errorf("no supermajority agreement on beacon_config (best: %.2f/%.2f weight)", field.Weight, report.Threshold)
+		}
+		return decodeBeaconConfig(field.Winner)
+	}
+
+	return nil, fmt.Errorf("no beacon_config field in consensus report")
+}
+
+// ConsensusReport exposes the last-computed per-field tally so
+// PrintOrderedPeers() (and the admin API) can render where BPs
+// disagree, not just the aggregate yes/no from ReachedConsensus().
+func (c *Network) ConsensusReport() *ConsensusReport {
+	return c.buildConsensusReport()
+}
+
+// ReachedConsensus reads the per-field tally of all top-weighted
+// peers and returns true iff every required launch artifact has a
+// value backed by a 2f+1-style supermajority of weight.
+func (c *Network) ReachedConsensus() bool {
+	if len(c.orderedPeers) == 0 {
+		return false
+	}
+	return c.buildConsensusReport().Agreed()
+}
+
+// ConsensusLaunchData stitches together a synthetic LaunchData from
+// the winning value of each individual field, rather than adopting a
+// single peer's whole record wholesale. It returns an error
+// enumerating every field still in disagreement.
+func (c *Network) ConsensusLaunchData() (*LaunchData, error) {
+	if len(c.orderedPeers) == 0 {
+		return nil, fmt.Errorf("no peers to reach consensus with")
+	}
+
+	report := c.buildConsensusReport()
+
+	var disagreements []string
+	for _, field := range report.Fields {
+		if !field.Agreed {
+			disagreements = append(disagreements, fmt.Sprintf("%s (best: %.2f/%.2f weight)", field.Field, field.Weight, report.Threshold))
+		}
+	}
+	if len(disagreements) > 0 {
+		return nil, fmt.Errorf("no consensus on: %s", strings.Join(disagreements, ", "))
+	}
+
+	out := &LaunchData{
+		Contracts: map[string]Contract{},
+	}
+
+	for _, field := range report.Fields {
+		switch {
+		case field.Field == "boot_sequence":
+			out.BootSequence = IPFSRef(field.Winner)
+		case field.Field == "snapshot":
+			out.Snapshot = IPFSRef(field.Winner)
+		case field.Field == "genesis_timestamp":
+			t, err := parseConsensusTimestamp(field.Winner)
+			if err != nil {
+				return nil, fmt.Errorf("genesis_timestamp: %s", err)
+			}
+			out.GenesisTimestamp = t
+		case field.Field == "initial_producers":
+			if field.Winner == "" {
+				out.InitialProducers = nil
+			} else {
+				out.InitialProducers = strings.Split(field.Winner, ",")
+			}
+		case strings.HasPrefix(field.Field, "contract:"):
+			parts := strings.SplitN(field.Field, ":", 3)
+			name, kind := parts[1], parts[2]
+			contract := out.Contracts[name]
+			if kind == "abi" {
+				contract.ABI = IPFSRef(field.Winner)
+			} else {
+				contract.Code = IPFSRef(field.Winner)
+			}
+			out.Contracts[name] = contract
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,53 @@
+package bios
+
+import "testing"
+
+func TestPickWinnerBreaksTiesDeterministically(t *testing.T) {
+	proposed := map[string]float64{
+		"zzz": 10,
+		"aaa": 10,
+		"mmm": 5,
+	}
+
+	winner, weight := pickWinner(proposed)
+	if winner != "aaa" || weight != 10 {
+		t.Fatalf("got winner %q (weight %.2f), want %q (weight 10), the lexicographically smallest of the tied candidates", winner, weight, "aaa")
+	}
+}
+
+func TestPickWinnerNoCandidates(t *testing.T) {
+	winner, weight := pickWinner(map[string]float64{})
+	if winner != "" || weight != 0 {
+		t.Fatalf("got (%q, %.2f), want (\"\", 0) for an empty proposal set", winner, weight)
+	}
+}
+
+func TestFieldAgreedNormalSupermajority(t *testing.T) {
+	proposed := map[string]float64{"a": 70, "b": 30}
+
+	if !fieldAgreed(66, 70, proposed, 2) {
+		t.Fatalf("expected 70 weight to clear a 66 threshold")
+	}
+	if fieldAgreed(66, 60, proposed, 2) {
+		t.Fatalf("expected 60 weight to not clear a 66 threshold")
+	}
+}
+
+func TestFieldAgreedZeroWeightBootstrap(t *testing.T) {
+	// A brand-new network where no peer has accrued any vouched
+	// weight yet: threshold and winnerWeight are both 0, but every
+	// peer unanimously proposed the same value.
+	unanimous := map[string]float64{"same-hash": 0}
+	if !fieldAgreed(0, 0, unanimous, 3) {
+		t.Fatalf("expected unanimous proposals to be agreed even with zero accumulated weight")
+	}
+
+	disagreeing := map[string]float64{"hash-a": 0, "hash-b": 0}
+	if fieldAgreed(0, 0, disagreeing, 3) {
+		t.Fatalf("expected disagreeing proposals to not be agreed even with zero accumulated weight")
+	}
+
+	if fieldAgreed(0, 0, unanimous, 0) {
+		t.Fatalf("expected no peers at all to never count as agreed")
+	}
+}
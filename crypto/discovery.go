@@ -0,0 +1,63 @@
+// Package crypto signs and verifies the documents peers exchange
+// during BIOS boot discovery: the Discovery file itself, and the
+// PeerLink vouches that one Discovery makes about another.
+package crypto
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// CanonicalDiscoveryBytes produces the deterministic, sorted-YAML
+// serialization of a Discovery's body that SignDiscovery and
+// VerifyDiscovery operate over. It must be called on the Discovery
+// with its Signature field blanked out (the signature obviously can't
+// be part of what it signs), but with PublicKey left in place: the
+// declared public key is itself covered by the signature, binding a
+// Discovery's identity to its signed content.
+func CanonicalDiscoveryBytes(disco interface{}) ([]byte, error) {
+	return yaml.Marshal(disco)
+}
+
+// SignDiscovery signs the canonical serialization of a Discovery's
+// body with priv, producing the detached signature to embed in its
+// Signature field.
+func SignDiscovery(priv ed25519.PrivateKey, canonicalBody []byte) []byte {
+	return ed25519.Sign(priv, canonicalBody)
+}
+
+// VerifyDiscovery checks that sig is a valid Ed25519 signature by pub
+// over the canonical serialization of a Discovery's body.
+func VerifyDiscovery(pub ed25519.PublicKey, canonicalBody []byte, sig []byte) bool {
+	if len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(pub, canonicalBody, sig)
+}
+
+// peerLinkMessage is the fixed tuple a vouching peer signs over: the
+// IPNS address it is vouching for, that peer's declared public key,
+// and the weight of the vouch. Binding the weight into the signature
+// means a PeerLink can't be reused with a different weight without
+// invalidating the vouch.
+func peerLinkMessage(targetIPNS string, targetPubKey []byte, weight float64) []byte {
+	return []byte(fmt.Sprintf("%s|%x|%.6f", targetIPNS, targetPubKey, weight))
+}
+
+// SignPeerLink signs a vouch for targetIPNS/targetPubKey/weight with
+// priv, producing the signature to embed in a PeerLink's
+// VoucherSignature field.
+func SignPeerLink(priv ed25519.PrivateKey, targetIPNS string, targetPubKey []byte, weight float64) []byte {
+	return ed25519.Sign(priv, peerLinkMessage(targetIPNS, targetPubKey, weight))
+}
+
+// VerifyPeerLink checks that sig is a valid vouch by pub (the
+// vouching peer's declared public key) over targetIPNS/targetPubKey/weight.
+func VerifyPeerLink(pub ed25519.PublicKey, targetIPNS string, targetPubKey []byte, weight float64, sig []byte) bool {
+	if len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(pub, peerLinkMessage(targetIPNS, targetPubKey, weight), sig)
+}
@@ -0,0 +1,64 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestSignAndVerifyDiscovery(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+
+	body := []byte("canonical discovery body")
+	sig := SignDiscovery(priv, body)
+
+	if !VerifyDiscovery(pub, body, sig) {
+		t.Fatalf("expected signature to verify")
+	}
+
+	if VerifyDiscovery(pub, []byte("tampered body"), sig) {
+		t.Fatalf("expected signature over a different body to fail")
+	}
+
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	if VerifyDiscovery(otherPub, body, sig) {
+		t.Fatalf("expected signature to fail against an unrelated public key")
+	}
+}
+
+func TestVerifyDiscoveryRejectsMalformedPublicKey(t *testing.T) {
+	if VerifyDiscovery(ed25519.PublicKey{0x01, 0x02}, []byte("body"), []byte("sig")) {
+		t.Fatalf("expected a malformed (wrong-size) public key to fail verification, not panic")
+	}
+}
+
+func TestSignAndVerifyPeerLink(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+
+	targetPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+
+	sig := SignPeerLink(priv, "/ipns/target", targetPub, 0.5)
+
+	if !VerifyPeerLink(pub, "/ipns/target", targetPub, 0.5, sig) {
+		t.Fatalf("expected vouch to verify")
+	}
+
+	if VerifyPeerLink(pub, "/ipns/target", targetPub, 0.75, sig) {
+		t.Fatalf("expected vouch signed over a different weight to fail, since weight is bound into the signed message")
+	}
+
+	if VerifyPeerLink(pub, "/ipns/other", targetPub, 0.5, sig) {
+		t.Fatalf("expected vouch signed over a different target to fail")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright © 2018 Alexandre Bourget <alex@eoscanada.com>
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+
+	bios "github.com/liuchengxu/eos-bios"
+)
+
+var ipfsClusterAPI string
+
+var pinClusterCmd = &cobra.Command{
+	Use:   "pin-cluster",
+	Short: "Seed an ipfs-cluster with the local discovery's launch artifacts.",
+	Long:  ``,
+	RunE:  runPinCluster,
+}
+
+func init() {
+	publishCmd.AddCommand(pinClusterCmd)
+	publishCmd.PersistentFlags().StringVarP(&ipfsClusterAPI, "ipfs-cluster-api", "", "", "Address of the ipfs-cluster HTTP API to replicate launch artifacts across (e.g. http://127.0.0.1:9094).")
+	viper.BindPFlag("ipfs_cluster_api", publishCmd.PersistentFlags().Lookup("ipfs-cluster-api"))
+}
+
+// runPinCluster pins only the local discovery's own launch artifacts
+// directly against the configured ipfs-cluster, without going through
+// bios.Network's full graph traversal: that would require live
+// IPNS/IPFS connectivity to every discovered peer just to pin our own
+// artifacts, and would pin whatever remote peers' artifacts the
+// traversal happened to reach too, not just ours.
+func runPinCluster(cmd *cobra.Command, args []string) error {
+	apiAddress := viper.GetString("ipfs_cluster_api")
+	if apiAddress == "" {
+		return fmt.Errorf("--ipfs-cluster-api is required")
+	}
+
+	discoveryFile := viper.GetString("discovery_file")
+	if discoveryFile == "" {
+		discoveryFile = "my_discovery_file.yaml"
+	}
+
+	rawDisco, err := ioutil.ReadFile(discoveryFile)
+	if err != nil {
+		return fmt.Errorf("reading %q: %s", discoveryFile, err)
+	}
+
+	var disco *bios.Discovery
+	if err := yaml.Unmarshal(rawDisco, &disco); err != nil {
+		return fmt.Errorf("parsing %q: %s", discoveryFile, err)
+	}
+
+	pinBackend := bios.NewClusterPinBackend(apiAddress)
+
+	refs := []bios.IPFSRef{disco.LaunchData.BootSequence, disco.LaunchData.Snapshot}
+	for _, contract := range disco.LaunchData.Contracts {
+		refs = append(refs, contract.ABI, contract.Code)
+	}
+
+	pinned := 0
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		if err := pinBackend.Pin(ref); err != nil {
+			return fmt.Errorf("pinning %q: %s", ref, err)
+		}
+		pinned++
+	}
+
+	fmt.Printf("Seeded ipfs-cluster with %d launch artifact(s) from %q.\n", pinned, discoveryFile)
+
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright © 2018 Alexandre Bourget <alex@eoscanada.com>
+
+package cmd
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	yaml "gopkg.in/yaml.v2"
+
+	bios "github.com/liuchengxu/eos-bios"
+	"github.com/liuchengxu/eos-bios/crypto"
+)
+
+var signDiscoveryKeyfile string
+
+var signDiscoveryCmd = &cobra.Command{
+	Use:   "sign-discovery",
+	Short: "Sign the local discovery file with an Ed25519 key, ready for IPFS publish.",
+	Long: `Loads an Ed25519 private key from disk (a keyfile, optionally
+protected by a passphrase read from EOS_BIOS_KEYFILE_PASSWORD), embeds
+the matching public key and a detached signature over the canonical
+body in the local discovery file, and rewrites it in place.`,
+	RunE: runSignDiscovery,
+}
+
+func init() {
+	publishCmd.AddCommand(signDiscoveryCmd)
+	signDiscoveryCmd.Flags().StringVarP(&signDiscoveryKeyfile, "keyfile", "", "", "Path to the Ed25519 keyfile used to sign the discovery file.")
+	viper.BindPFlag("keyfile", signDiscoveryCmd.Flags().Lookup("keyfile"))
+	viper.BindEnv("keyfile_password", "EOS_BIOS_KEYFILE_PASSWORD")
+}
+
+func runSignDiscovery(cmd *cobra.Command, args []string) error {
+	keyfile := viper.GetString("keyfile")
+	if keyfile == "" {
+		return fmt.Errorf("--keyfile is required")
+	}
+
+	priv, err := loadSigningKey(keyfile)
+	if err != nil {
+		return fmt.Errorf("loading signing key: %s", err)
+	}
+
+	discoveryFile := viper.GetString("discovery_file")
+	if discoveryFile == "" {
+		discoveryFile = "my_discovery_file.yaml"
+	}
+
+	rawDisco, err := ioutil.ReadFile(discoveryFile)
+	if err != nil {
+		return fmt.Errorf("reading %q: %s", discoveryFile, err)
+	}
+
+	var disco *bios.Discovery
+	if err := yaml.Unmarshal(rawDisco, &disco); err != nil {
+		return fmt.Errorf("parsing %q: %s", discoveryFile, err)
+	}
+
+	disco.PublicKey = priv.Public().(ed25519.PublicKey)
+	disco.Signature = nil
+
+	canonical, err := crypto.CanonicalDiscoveryBytes(disco)
+	if err != nil {
+		return fmt.Errorf("canonicalizing body: %s", err)
+	}
+	disco.Signature = crypto.SignDiscovery(priv, canonical)
+
+	signedDisco, err := yaml.Marshal(disco)
+	if err != nil {
+		return fmt.Errorf("marshaling signed discovery: %s", err)
+	}
+
+	if err := ioutil.WriteFile(discoveryFile, signedDisco, 0644); err != nil {
+		return fmt.Errorf("writing %q: %s", discoveryFile, err)
+	}
+
+	fmt.Printf("Signed %q with public key %x\n", discoveryFile, disco.PublicKey)
+
+	return nil
+}
+
+// loadSigningKey reads a raw 64-byte Ed25519 private key from
+// keyfile, decrypting it first with EOS_BIOS_KEYFILE_PASSWORD when
+// the file is password-protected.
+func loadSigningKey(keyfile string) (ed25519.PrivateKey, error) {
+	raw, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		return nil, err
+	}
+
+	if password := viper.GetString("keyfile_password"); password != "" {
+		raw, err = decryptKeyfile(raw, password)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting keyfile: %s", err)
+		}
+	}
+
+	if len(raw) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("expected a %d-byte Ed25519 private key, got %d bytes", ed25519.PrivateKeySize, len(raw))
+	}
+
+	return ed25519.PrivateKey(raw), nil
+}
+
+const (
+	keyfileSaltSize  = 16
+	keyfileNonceSize = 24
+)
+
+// decryptKeyfile unwraps a keyfile laid out as salt || nonce ||
+// secretbox-sealed key, deriving the secretbox key from password via
+// scrypt. This is the same construction NaCl-based CLIs (e.g. the
+// one in eosio/eosjs-ecc) use for password-protected keystores.
+func decryptKeyfile(raw []byte, password string) ([]byte, error) {
+	if len(raw) < keyfileSaltSize+keyfileNonceSize {
+		return nil, errors.New("keyfile too short to contain salt and nonce")
+	}
+
+	salt := raw[:keyfileSaltSize]
+	var nonce [keyfileNonceSize]byte
+	copy(nonce[:], raw[keyfileSaltSize:keyfileSaltSize+keyfileNonceSize])
+	sealed := raw[keyfileSaltSize+keyfileNonceSize:]
+
+	derivedKey, err := scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %s", err)
+	}
+	var secretboxKey [32]byte
+	copy(secretboxKey[:], derivedKey)
+
+	opened, ok := secretbox.Open(nil, sealed, &nonce, &secretboxKey)
+	if !ok {
+		return nil, errors.New("wrong password or corrupted keyfile")
+	}
+
+	return opened, nil
+}
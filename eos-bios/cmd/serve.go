@@ -0,0 +1,75 @@
+// Copyright © 2018 Alexandre Bourget <alex@eoscanada.com>
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	uberfx "go.uber.org/fx"
+
+	"github.com/liuchengxu/eos-bios/api"
+	biosfx "github.com/liuchengxu/eos-bios/fx"
+)
+
+var httpAddr string
+var httpAPI string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a read-only admin HTTP/JSON-RPC API over the peer graph.",
+	Long: `Starts a long-running daemon that re-uses the same Network
+singleton as the rest of eos-bios, and exposes it over HTTP+JSON-RPC
+plus a Server-Sent Events stream at /events, so dashboards and tooling
+can watch BP vouching shift during the final hours of a launch.`,
+	RunE: runServe,
+}
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVarP(&httpAddr, "http.addr", "", "127.0.0.1:8099", "Address to bind the admin HTTP/JSON-RPC API to.")
+	serveCmd.Flags().StringVarP(&httpAPI, "http.api", "", "peers,consensus", "Comma-separated list of enabled API namespaces: peers,consensus,cache,beacon.")
+	viper.BindPFlag("http.addr", serveCmd.Flags().Lookup("http.addr"))
+	viper.BindPFlag("http.api", serveCmd.Flags().Lookup("http.api"))
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	discoveryFile := viper.GetString("discovery_file")
+	if discoveryFile == "" {
+		discoveryFile = "my_discovery_file.yaml"
+	}
+
+	cfg := biosfx.Config{
+		IPFSAPIAddress: viper.GetString("ipfs-api-address"),
+		CachePath:      viper.GetString("cache_path"),
+		DiscoveryFile:  discoveryFile,
+		UseCache:       viper.GetBool("use_cache"),
+		IPFSClusterAPI: viper.GetString("ipfs_cluster_api"),
+		HTTPAddr:       viper.GetString("http.addr"),
+		HTTPAPI:        viper.GetString("http.api"),
+	}
+
+	app := uberfx.New(
+		biosfx.BIOSModule,
+		uberfx.Supply(cfg),
+		// Depending on *api.Server is what makes Fx actually construct
+		// it (and provideNetwork beneath it) and register their
+		// OnStart hooks; the serving itself happens in those hooks.
+		uberfx.Invoke(func(*api.Server) {}),
+	)
+
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		return err
+	}
+	defer app.Stop(ctx)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	<-sig
+
+	return nil
+}
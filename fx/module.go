@@ -0,0 +1,142 @@
+// Package fx wires bios.IPFS, bios.Network, bios.PinBackend and the
+// admin api.Server together as an uber-go/fx module (it provides no
+// signing keystore), so cobra
+// commands stop hand-constructing them in the order that happens to
+// work and instead declare what they need.
+package fx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+
+	bios "github.com/liuchengxu/eos-bios"
+	"github.com/liuchengxu/eos-bios/api"
+)
+
+// Config is the set of values a cobra command collects from flags and
+// hands to the module; everything downstream (IPFS, Network, the pin
+// backend, the API server) is derived from it.
+type Config struct {
+	IPFSAPIAddress   string
+	CachePath        string
+	DiscoveryFile    string
+	UseCache         bool
+	IPFSClusterAPI   string
+	HTTPAddr         string
+	HTTPAPI          string
+	GraphRefreshTick time.Duration
+}
+
+// BIOSModule provides the shared IPFS/Network/PinBackend/API
+// singletons. Cobra commands depend on fx.New(BIOSModule,
+// fx.Invoke(runSomething)) instead of constructing these by hand.
+var BIOSModule = fx.Options(
+	fx.Provide(
+		provideIPFS,
+		provideNetwork,
+		providePinBackend,
+		provideAPIServer,
+	),
+)
+
+func provideIPFS(cfg Config) *bios.IPFS {
+	return bios.NewIPFS(cfg.IPFSAPIAddress)
+}
+
+// providePinBackend builds the PinBackend from Config alone (never
+// from a *bios.Network) so it has no edge back to provideNetwork:
+// provideNetwork depends on bios.PinBackend to wire it in via
+// SetPinBackend, and a PinBackend depending on *bios.Network in turn
+// would make that a cycle Fx can't resolve.
+func providePinBackend(cfg Config) bios.PinBackend {
+	if cfg.IPFSClusterAPI == "" {
+		return bios.NewLocalPinBackendForCachePath(cfg.CachePath)
+	}
+	return bios.NewClusterPinBackend(cfg.IPFSClusterAPI)
+}
+
+// provideNetwork builds the Network, wires in the requested
+// PinBackend, and registers its lifecycle: OnStart runs one
+// synchronous UpdateGraph() so the graph is ready before the
+// command's Invoke runs, then kicks off a background refresh
+// goroutine; OnStop cancels that goroutine.
+func provideNetwork(lc fx.Lifecycle, cfg Config, ipfs *bios.IPFS, pin bios.PinBackend) *bios.Network {
+	net := bios.NewNetwork(cfg.CachePath, cfg.DiscoveryFile, ipfs)
+	net.UseCache = cfg.UseCache
+	net.SetPinBackend(pin)
+
+	stop := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := net.UpdateGraph(); err != nil {
+				return fmt.Errorf("initial graph update: %s", err)
+			}
+
+			tick := cfg.GraphRefreshTick
+			if tick == 0 {
+				tick = 2 * time.Minute
+			}
+
+			go func() {
+				ticker := time.NewTicker(tick)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						if err := net.UpdateGraph(); err != nil {
+							fmt.Printf("background graph refresh: %s\n", err)
+						}
+					case <-stop:
+						return
+					}
+				}
+			}()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(stop)
+			return nil
+		},
+	})
+
+	return net
+}
+
+// provideAPIServer builds the admin API server around the shared
+// Network and registers its own OnStart/OnStop so `serve` becomes a
+// thin fx.Invoke.
+func provideAPIServer(lc fx.Lifecycle, cfg Config, net *bios.Network) *api.Server {
+	var namespaces []string
+	if cfg.HTTPAPI != "" {
+		namespaces = strings.Split(cfg.HTTPAPI, ",")
+	}
+
+	srv := api.NewServer(net, namespaces)
+
+	// Wire the background graph-refresh ticker (and any manual
+	// graph.refresh call) into the same SSE notification path, so
+	// "/events" subscribers see every update, not just explicit ones.
+	net.OnGraphUpdated = srv.NotifyGraphUpdated
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if cfg.HTTPAddr == "" {
+				return nil
+			}
+			go func() {
+				if err := srv.ListenAndServe(cfg.HTTPAddr); err != nil {
+					fmt.Printf("admin API server stopped: %s\n", err)
+				}
+			}()
+			return nil
+		},
+	})
+
+	return srv
+}
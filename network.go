@@ -15,6 +15,8 @@ import (
 
 	multihash "github.com/multiformats/go-multihash"
 	"github.com/ryanuber/columnize"
+
+	"github.com/liuchengxu/eos-bios/crypto"
 )
 
 type Network struct {
@@ -30,15 +32,33 @@ type Network struct {
 	discoveredPeers map[IPFSRef]*Peer
 	orderedPeers    []*Peer
 
+	pinBackend PinBackend
+
 	lastFetch time.Time
+
+	// OnGraphUpdated, if set, is called after every successful
+	// traverseGraph/verifyGraph/calculateWeights/verifyPinning pass, whether
+	// triggered by the throttled background ticker or an explicit
+	// ForceUpdateGraph. The admin API server hooks in here to emit its
+	// "/events" SSE notifications.
+	OnGraphUpdated func()
 }
 
 func NewNetwork(cachePath string, myDiscoveryFile string, ipfs *IPFS) *Network {
-	return &Network{
+	net := &Network{
 		IPFS:            ipfs,
 		cachePath:       cachePath,
 		myDiscoveryFile: myDiscoveryFile,
 	}
+	net.pinBackend = NewLocalPinBackend(net)
+	return net
+}
+
+// SetPinBackend overrides the default LocalPinBackend, e.g. with a
+// ClusterPinBackend pointed at an ipfs-cluster for durable, replicated
+// storage of the launch artifacts.
+func (c *Network) SetPinBackend(backend PinBackend) {
+	c.pinBackend = backend
 }
 
 func (c *Network) ensureExists() error {
@@ -50,6 +70,17 @@ func (net *Network) UpdateGraph() error {
 		return nil
 	}
 
+	return net.doUpdateGraph()
+}
+
+// ForceUpdateGraph re-runs the graph traversal/verification/weighting
+// immediately, bypassing the 2-minute throttle UpdateGraph() applies.
+// Used by the admin API's graph.refresh method.
+func (net *Network) ForceUpdateGraph() error {
+	return net.doUpdateGraph()
+}
+
+func (net *Network) doUpdateGraph() error {
 	if err := net.traverseGraph(); err != nil {
 		return fmt.Errorf("traversing graph: %s", err)
 	}
@@ -62,6 +93,14 @@ func (net *Network) UpdateGraph() error {
 		return fmt.Errorf("calculating weights: %s", err)
 	}
 
+	if err := net.verifyPinning(); err != nil {
+		return fmt.Errorf("verifying pinning: %s", err)
+	}
+
+	if net.OnGraphUpdated != nil {
+		net.OnGraphUpdated()
+	}
+
 	return nil
 }
 
@@ -107,8 +146,18 @@ func (c *Network) traversePeer(disco *Discovery, ipnsRef IPNSRef, ipfsRef IPFSRe
 		return err
 	}
 
+	if err := verifyDiscoverySignature(disco); err != nil {
+		return fmt.Errorf("discovery signature: %s", err)
+	}
+
 	launchData := disco.LaunchData
 
+	for _, peerLink := range launchData.Peers {
+		if err := verifyPeerLinkSignature(disco, peerLink); err != nil {
+			return fmt.Errorf("peer link %q: %s", peerLink.DiscoveryLink, err)
+		}
+	}
+
 	// Go through all the things we can download from there
 	if err := c.DownloadIPFSRef(launchData.BootSequence); err != nil {
 		return fmt.Errorf("boot_sequence: %s", err)
@@ -128,6 +177,10 @@ func (c *Network) traversePeer(disco *Discovery, ipnsRef IPNSRef, ipfsRef IPFSRe
 		}
 	}
 
+	if err := c.pinLaunchArtifacts(launchData); err != nil {
+		return fmt.Errorf("pinning launch artifacts: %s", err)
+	}
+
 	c.discoveredPeers[ipfsRef] = &Peer{
 		DiscoveryFile: ipfsRef,
 		DiscoveryLink: ipnsRef,
@@ -229,6 +282,111 @@ func (c *Network) DownloadIPFSRef(ref IPFSRef) error {
 	return nil
 }
 
+// pinLaunchArtifacts hands every IPFS ref of a peer's LaunchData to
+// the configured PinBackend, so the boot_sequence, snapshot and
+// contract code/ABI end up replicated rather than living solely in
+// whichever single node happened to fetch them first.
+func (c *Network) pinLaunchArtifacts(launchData LaunchData) error {
+	refs := []IPFSRef{launchData.BootSequence, launchData.Snapshot}
+	for _, contract := range launchData.Contracts {
+		refs = append(refs, contract.ABI, contract.Code)
+	}
+
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		if err := c.pinBackend.Pin(ref); err != nil {
+			return fmt.Errorf("pinning %q: %s", ref, err)
+		}
+	}
+
+	return nil
+}
+
+// top21ClusterPeerIDs returns the declared ClusterPeerID of every one
+// of the top 21 BPs that bothered to declare one, which is the actual
+// BP identity set verifyPinning measures replication against. BPs
+// that didn't declare a ClusterPeerID can't be checked and are simply
+// left out of the denominator, same as they'd be left out of any
+// other per-BP accounting we don't have data for.
+func (c *Network) top21ClusterPeerIDs() []PeerID {
+	top := c.orderedPeers
+	if len(top) > 21 {
+		top = top[:21]
+	}
+
+	var ids []PeerID
+	for _, peer := range top {
+		if id := peer.Discovery.ClusterPeerID; id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// verifyPinning refuses to let UpdateGraph declare the graph ready
+// for consensus unless every pinned launch artifact (boot_sequence,
+// snapshot, and every contract's ABI/code) is pinned on at least 2/3
+// of the top 21 BPs' own declared ipfs-cluster peers -- not just an
+// arbitrary count of whatever ipfs-cluster peers happen to be known,
+// which would let a handful of unrelated cluster nodes "pass" a
+// launch that most BPs never actually pinned anything for.
+func (c *Network) verifyPinning() error {
+	// Without a replicated backend configured, pinning is still just
+	// local caching: don't gate consensus on it.
+	if _, local := c.pinBackend.(*LocalPinBackend); local {
+		return nil
+	}
+
+	if len(c.orderedPeers) == 0 {
+		return nil
+	}
+
+	bpClusterPeerIDs := c.top21ClusterPeerIDs()
+	if len(bpClusterPeerIDs) == 0 {
+		return fmt.Errorf("no top-21 BP has declared a cluster_peer_id, can't verify pinning replication")
+	}
+
+	launchData, err := c.ConsensusLaunchData()
+	if err != nil {
+		launchData = &c.orderedPeers[0].Discovery.LaunchData
+	}
+
+	refs := []IPFSRef{launchData.BootSequence, launchData.Snapshot}
+	for _, contract := range launchData.Contracts {
+		refs = append(refs, contract.ABI, contract.Code)
+	}
+
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+
+		statuses := c.pinBackend.PeerStatuses(ref)
+
+		pinned := 0
+		for _, id := range bpClusterPeerIDs {
+			if statuses[id] == PinStatusPinned {
+				pinned++
+			}
+		}
+
+		if !meetsPinningThreshold(pinned, len(bpClusterPeerIDs)) {
+			return fmt.Errorf("%q only pinned on %d/%d top-21 BPs' ipfs-cluster peers", ref, pinned, len(bpClusterPeerIDs))
+		}
+	}
+
+	return nil
+}
+
+// meetsPinningThreshold reports whether pinned clears a 2/3
+// supermajority of total, the same 2f+1-style threshold
+// consensusThreshold() applies to weight.
+func meetsPinningThreshold(pinned, total int) bool {
+	return float64(pinned) >= (2.0/3.0)*float64(total)
+}
+
 func toMultihash(cnt []byte) IPFSRef {
 	hash, _ := multihash.Sum(cnt, multihash.SHA2_256, 32)
 	return IPFSRef(fmt.Sprintf("/ipfs/%s", hash.B58String()))
@@ -240,7 +398,15 @@ func (c *Network) writeToCache(ref string, content []byte) error {
 }
 
 func (c *Network) isInCache(ref string) bool {
-	fileName := filepath.Join(c.cachePath, replaceAllWeirdities(string(ref)))
+	return isInCachePath(c.cachePath, ref)
+}
+
+// isInCachePath is the cache-path-only half of Network.isInCache,
+// factored out so LocalPinBackend can check the cache without holding
+// a *Network (which would create an Fx provider cycle: Network wants
+// a PinBackend, and a Network-backed PinBackend would want a Network).
+func isInCachePath(cachePath, ref string) bool {
+	fileName := filepath.Join(cachePath, replaceAllWeirdities(string(ref)))
 
 	if _, err := os.Stat(fileName); err == nil {
 		return true
@@ -277,10 +443,35 @@ func (c *Network) ValidateLocalFile(filename string) error {
 	return nil
 }
 
+// ChainID derives a publicly verifiable, unpredictable-yet-agreed-upon
+// chain ID by mixing the hash of the consensus launch data with the
+// randomness of the drand beacon round named by the BeaconConfig that
+// won the weighted supermajority of top-weighted peers. This means no single BP can
+// grind the chain ID by withholding or replaying its own launch data.
 func (c *Network) ChainID() []byte {
-	// TODO: compute based on all the hashes in the elected launchdata?
-	// have a value be voted for ?
-	return make([]byte, 32, 32)
+	launchData, err := c.ConsensusLaunchData()
+	if err != nil {
+		return make([]byte, 32, 32)
+	}
+
+	cfg, err := c.consensusBeaconConfig()
+	if err != nil {
+		fmt.Printf("computing chain id: %s\n", err)
+		return make([]byte, 32, 32)
+	}
+
+	beacon, err := c.fetchBeacon(cfg)
+	if err != nil {
+		fmt.Printf("computing chain id: fetching beacon: %s\n", err)
+		return make([]byte, 32, 32)
+	}
+
+	launchDataHash := sha2([]byte(fmt.Sprintf("%v", launchData)))
+
+	hash := sha256.New()
+	hash.Write([]byte(launchDataHash))
+	hash.Write([]byte(beacon.Randomness))
+	return hash.Sum(nil)
 }
 
 func (c *Network) calculateWeights() error {
@@ -311,6 +502,15 @@ func (c *Network) calculateWeights() error {
 				continue
 			}
 
+			if err := verifyPeerLinkSignature(peer.Discovery, peerLink); err != nil {
+				fmt.Printf("  - refusing to apply vouch weight from %q: %s\n", peer.Discovery.EOSIOAccountName, err)
+				continue
+			}
+			if !peerLink.TargetPublicKey.Equal(peerLinkDisco.Discovery.PublicKey) {
+				fmt.Printf("  - refusing to apply vouch weight from %q: target_public_key doesn't match %q's public_key\n", peer.Discovery.EOSIOAccountName, peerLinkDisco.AccountName())
+				continue
+			}
+
 			fmt.Println("adding weight to", peerLinkDisco.AccountName())
 			// Weight defaults to 0.0
 			peerLinkDisco.TotalWeight += peerLink.Weight
@@ -344,6 +544,52 @@ func (c *Network) verifyGraph() error {
 			return fmt.Errorf("two peers claim the eosio_account_name %q: %q and %q", peer.Discovery.EOSIOAccountName, discoURL, peer.DiscoveryFile)
 		}
 	}
+
+	// BeaconConfig agreement is handled like every other launch
+	// artifact: it's one of the fields buildConsensusReport() tallies
+	// by weight, so a zero-weight bystander peer declaring some other
+	// beacon can't block the launch the way an all-or-nothing check
+	// across every discovered peer would. See consensusFieldsForPeer.
+
+	return nil
+}
+
+// verifyDiscoverySignature checks that a Discovery carries a valid
+// Ed25519 signature, by its own declared PublicKey, over its
+// canonical (sorted-YAML) body. Unsigned or mis-signed discoveries
+// are rejected outright: we no longer trust a discovery file just
+// because it passed shape validation.
+func verifyDiscoverySignature(disco *Discovery) error {
+	if len(disco.PublicKey) == 0 || len(disco.Signature) == 0 {
+		return errors.New("missing public_key or signature")
+	}
+
+	unsigned := *disco
+	unsigned.Signature = nil
+	canonical, err := crypto.CanonicalDiscoveryBytes(&unsigned)
+	if err != nil {
+		return fmt.Errorf("canonicalizing body: %s", err)
+	}
+
+	if !crypto.VerifyDiscovery(disco.PublicKey, canonical, disco.Signature) {
+		return errors.New("signature does not verify against public_key")
+	}
+
+	return nil
+}
+
+// verifyPeerLinkSignature checks that peerLink carries a valid vouch,
+// signed by voucher's own PublicKey, over the
+// (target_ipns, target_pubkey, weight) tuple it claims.
+func verifyPeerLinkSignature(voucher *Discovery, peerLink *PeerLink) error {
+	if len(peerLink.VoucherSignature) == 0 {
+		return errors.New("missing voucher_signature")
+	}
+
+	if !crypto.VerifyPeerLink(voucher.PublicKey, string(peerLink.DiscoveryLink), peerLink.TargetPublicKey, peerLink.Weight, peerLink.VoucherSignature) {
+		return errors.New("voucher_signature does not verify against voucher's public_key")
+	}
+
 	return nil
 }
 
@@ -372,26 +618,31 @@ func (c *Network) PrintOrderedPeers() {
 	fmt.Println(columnize.SimpleFormat(columns))
 
 	fmt.Println("")
+	c.printConsensusReport()
 	fmt.Println("###############################################################################################")
 	fmt.Println("")
 }
 
-// ReachedConsensus reads all the hashes of the top-level peers and
-// returns true if we have reached an agreement on the content to
-// inject in the chain.
-func (c *Network) ReachedConsensus() bool {
-	// TODO: Implement the logic that determines the consensus.. right
-	// now it's just the weights in order.. and the top-most wins: we use
-	// its configuration.
-	return true
-}
+func (c *Network) printConsensusReport() {
+	report := c.ConsensusReport()
 
-func (c *Network) ConsensusLaunchData() (*LaunchData, error) {
-	// TODO: implement the algo to create a Discovery file based on
-	// the most vouched for hashes for all the components.
-	//
-	// Will that work ? Will that make sense ?
-	//
-	// Cycle through the top peers, take the most vetted
-	return &(c.orderedPeers[0].Discovery.LaunchData), nil
+	fmt.Println("################################    CONSENSUS REPORT    #######################################")
+	fmt.Println("")
+
+	reportColumns := []string{
+		"Field | Agreed | Winning Weight | Threshold",
+		"----- | ------ | -------------- | ---------",
+	}
+	for _, field := range report.Fields {
+		status := "NO"
+		if field.Agreed {
+			status = "yes"
+		}
+		reportColumns = append(reportColumns, fmt.Sprintf("%s | %s | %.2f | %.2f", field.Field, status, field.Weight, report.Threshold))
+	}
+	fmt.Println(columnize.SimpleFormat(reportColumns))
+	fmt.Println("")
 }
+
+// ReachedConsensus and ConsensusLaunchData live in consensus.go,
+// which implements the real per-field weighted-agreement algorithm.
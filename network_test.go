@@ -0,0 +1,22 @@
+package bios
+
+import "testing"
+
+func TestMeetsPinningThreshold(t *testing.T) {
+	cases := []struct {
+		pinned, total int
+		want          bool
+	}{
+		{pinned: 3, total: 3, want: true},
+		{pinned: 2, total: 3, want: true},
+		{pinned: 1, total: 3, want: false},
+		{pinned: 0, total: 3, want: false},
+		{pinned: 0, total: 0, want: true},
+	}
+
+	for _, c := range cases {
+		if got := meetsPinningThreshold(c.pinned, c.total); got != c.want {
+			t.Errorf("meetsPinningThreshold(%d, %d) = %v, want %v", c.pinned, c.total, got, c.want)
+		}
+	}
+}
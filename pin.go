@@ -0,0 +1,194 @@
+package bios
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// PeerID identifies a participant in a pinning cluster (for
+// ClusterPinBackend, an ipfs-cluster peer ID; for LocalPinBackend,
+// simply "local").
+type PeerID string
+
+// PinStatus is the lifecycle state of a single pinned artifact on a
+// given peer.
+type PinStatus string
+
+const (
+	PinStatusUnpinned PinStatus = "unpinned"
+	PinStatusQueued   PinStatus = "queued"
+	PinStatusPinning  PinStatus = "pinning"
+	PinStatusPinned   PinStatus = "pinned"
+	PinStatusError    PinStatus = "error"
+)
+
+// PinBackend pins launch artifacts somewhere durable so the multi-GB
+// snapshot and boot_sequence don't depend on a single BP's local
+// cache. LocalPinBackend is the zero-config default; ClusterPinBackend
+// replicates across an ipfs-cluster.
+type PinBackend interface {
+	Pin(ref IPFSRef) error
+	Unpin(ref IPFSRef) error
+	Status(ref IPFSRef) (PinStatus, error)
+	PeerStatuses(ref IPFSRef) map[PeerID]PinStatus
+}
+
+// LocalPinBackend is the PinBackend used when no ipfs-cluster is
+// configured: it reports an artifact pinned iff it's present in the
+// given disk cache, same as the pre-existing behavior. It takes a
+// cachePath rather than a *Network so it can be constructed (e.g. by
+// the Fx module) independently of the Network that will end up using
+// it.
+type LocalPinBackend struct {
+	cachePath string
+}
+
+func NewLocalPinBackend(net *Network) *LocalPinBackend {
+	return &LocalPinBackend{cachePath: net.cachePath}
+}
+
+// NewLocalPinBackendForCachePath builds a LocalPinBackend directly
+// from a cache path, without requiring a *Network.
+func NewLocalPinBackendForCachePath(cachePath string) *LocalPinBackend {
+	return &LocalPinBackend{cachePath: cachePath}
+}
+
+func (b *LocalPinBackend) Pin(ref IPFSRef) error {
+	if !isInCachePath(b.cachePath, string(ref)) {
+		return fmt.Errorf("%q not in local cache, can't pin", ref)
+	}
+	return nil
+}
+
+func (b *LocalPinBackend) Unpin(ref IPFSRef) error {
+	return nil
+}
+
+func (b *LocalPinBackend) Status(ref IPFSRef) (PinStatus, error) {
+	if isInCachePath(b.cachePath, string(ref)) {
+		return PinStatusPinned, nil
+	}
+	return PinStatusUnpinned, nil
+}
+
+func (b *LocalPinBackend) PeerStatuses(ref IPFSRef) map[PeerID]PinStatus {
+	status, _ := b.Status(ref)
+	return map[PeerID]PinStatus{"local": status}
+}
+
+// ClusterPinBackend pins launch artifacts through an ipfs-cluster
+// HTTP API, so they're replicated across every participating BP
+// rather than just fetched locally.
+type ClusterPinBackend struct {
+	APIAddress string
+	httpClient *http.Client
+}
+
+func NewClusterPinBackend(apiAddress string) *ClusterPinBackend {
+	return &ClusterPinBackend{
+		APIAddress: apiAddress,
+		httpClient: &http.Client{},
+	}
+}
+
+func (b *ClusterPinBackend) cidFromRef(ref IPFSRef) string {
+	return string(ref[len("/ipfs/"):])
+}
+
+func (b *ClusterPinBackend) Pin(ref IPFSRef) error {
+	url := fmt.Sprintf("%s/pins/%s", b.APIAddress, b.cidFromRef(ref))
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling ipfs-cluster pin API: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("ipfs-cluster pin API returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (b *ClusterPinBackend) Unpin(ref IPFSRef) error {
+	url := fmt.Sprintf("%s/pins/%s", b.APIAddress, b.cidFromRef(ref))
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling ipfs-cluster unpin API: %s", err)
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+type clusterPinStatusResponse struct {
+	Cid     string                     `json:"cid"`
+	PeerMap map[string]clusterPeerPins `json:"peer_map"`
+}
+
+type clusterPeerPins struct {
+	Status string `json:"status"`
+}
+
+func (b *ClusterPinBackend) fetchStatus(ref IPFSRef) (*clusterPinStatusResponse, error) {
+	url := fmt.Sprintf("%s/pins/%s", b.APIAddress, b.cidFromRef(ref))
+	resp, err := b.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("calling ipfs-cluster status API: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+
+	var status clusterPinStatusResponse
+	if err := json.Unmarshal(buf.Bytes(), &status); err != nil {
+		return nil, fmt.Errorf("decoding ipfs-cluster status response: %s", err)
+	}
+
+	return &status, nil
+}
+
+func (b *ClusterPinBackend) Status(ref IPFSRef) (PinStatus, error) {
+	status, err := b.fetchStatus(ref)
+	if err != nil {
+		return PinStatusError, err
+	}
+
+	for _, peer := range status.PeerMap {
+		if PinStatus(peer.Status) == PinStatusPinned {
+			return PinStatusPinned, nil
+		}
+	}
+
+	return PinStatusUnpinned, nil
+}
+
+func (b *ClusterPinBackend) PeerStatuses(ref IPFSRef) map[PeerID]PinStatus {
+	out := map[PeerID]PinStatus{}
+
+	status, err := b.fetchStatus(ref)
+	if err != nil {
+		return out
+	}
+
+	for peerID, peer := range status.PeerMap {
+		out[PeerID(peerID)] = PinStatus(peer.Status)
+	}
+
+	return out
+}
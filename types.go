@@ -0,0 +1,190 @@
+package bios
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// IPFSRef is a content-addressed "/ipfs/<hash>" reference, as found
+// throughout a Discovery's LaunchData.
+type IPFSRef string
+
+// IPNSRef is a mutable "/ipns/<name>" reference that a peer's
+// discovery file is published and updated under.
+type IPNSRef string
+
+// Contract is one smart contract's code and ABI, each pinned
+// separately on IPFS.
+type Contract struct {
+	ABI  IPFSRef `yaml:"abi"`
+	Code IPFSRef `yaml:"code"`
+}
+
+// LaunchData is the set of artifacts and parameters a Discovery
+// proposes for the chain launch: what to boot with, and who to boot
+// alongside.
+type LaunchData struct {
+	BootSequence         IPFSRef             `yaml:"boot_sequence"`
+	Snapshot             IPFSRef             `yaml:"snapshot"`
+	SnapshotUnregistered IPFSRef             `yaml:"snapshot_unregistered,omitempty"`
+	Contracts            map[string]Contract `yaml:"contracts"`
+	Peers                []*PeerLink         `yaml:"peers"`
+
+	// GenesisTimestamp and InitialProducers are consensus fields in
+	// their own right (see consensusFieldsForPeer): every top-weighted
+	// peer must propose the same genesis time and producer set for
+	// ConsensusLaunchData() to agree on one.
+	GenesisTimestamp time.Time `yaml:"genesis_timestamp"`
+	InitialProducers []string  `yaml:"initial_producers"`
+}
+
+// PeerLink is one entry in a Discovery's Peers list: a vouch for
+// another peer's discovery file, at a given weight.
+type PeerLink struct {
+	DiscoveryLink IPNSRef `yaml:"discovery_link"`
+	Comment       string  `yaml:"comment,omitempty"`
+	Weight        float64 `yaml:"weight"`
+
+	// TargetPublicKey is the vouched-for peer's own declared
+	// PublicKey, bound into VoucherSignature so a vouch can't be
+	// replayed against a different peer reusing the same IPNS name.
+	TargetPublicKey ed25519.PublicKey `yaml:"target_public_key"`
+	// VoucherSignature is this PeerLink's voucher's signature (by the
+	// Discovery that contains this PeerLink) over
+	// (DiscoveryLink, TargetPublicKey, Weight).
+	VoucherSignature []byte `yaml:"voucher_signature"`
+}
+
+// Discovery is the document each peer publishes (and others fetch
+// over IPNS) describing who they are and what launch data they
+// propose.
+type Discovery struct {
+	EOSIOAccountName string     `yaml:"eosio_account_name"`
+	OrganizationName string     `yaml:"organization_name"`
+	LaunchData       LaunchData `yaml:"launch_data"`
+
+	// BeaconConfig declares the drand beacon this peer proposes to
+	// seed the chain ID with. See beacon.go.
+	BeaconConfig *BeaconConfig `yaml:"beacon_config,omitempty"`
+
+	// ClusterPeerID is this peer's own ipfs-cluster node identity, if
+	// it runs one. It's what lets verifyPinning map a PeerStatuses
+	// result (keyed by ipfs-cluster PeerID) back to an actual BP
+	// instead of an anonymous cluster-peer count.
+	ClusterPeerID PeerID `yaml:"cluster_peer_id,omitempty"`
+
+	// PublicKey and Signature are this Discovery's own identity and
+	// the detached signature over its canonical body (see
+	// bios/crypto). A Discovery without both is rejected outright.
+	PublicKey ed25519.PublicKey `yaml:"public_key"`
+	Signature []byte            `yaml:"signature"`
+}
+
+// Peer is a node in the discovered graph: a Discovery plus the
+// bookkeeping (where it was fetched from, how much weight it has
+// accrued) that only makes sense in the context of a traversal.
+type Peer struct {
+	Discovery     *Discovery
+	DiscoveryLink IPNSRef
+	DiscoveryFile IPFSRef
+
+	TotalWeight float64
+}
+
+// AccountName is a shorthand for the wrapped Discovery's
+// EOSIOAccountName, used when formatting peer tables and API
+// responses.
+func (p *Peer) AccountName() string {
+	return p.Discovery.EOSIOAccountName
+}
+
+// Columns renders a Peer as a row of the "IPNS Link | Account |
+// Organization | Weight" table PrintOrderedPeers() builds.
+func (p *Peer) Columns() string {
+	return fmt.Sprintf("%s | %s | %s | %.2f", p.DiscoveryLink, p.AccountName(), p.Discovery.OrganizationName, p.TotalWeight)
+}
+
+// ValidateDiscovery runs the shape checks a Discovery must pass
+// before it's trusted enough to even check its signature:
+// EOSIOAccountName is what every other peer's vouches and the
+// consensus tally key off, so it has to be present.
+func ValidateDiscovery(disco *Discovery) error {
+	if disco.EOSIOAccountName == "" {
+		return fmt.Errorf("missing eosio_account_name")
+	}
+	return nil
+}
+
+// yamlUnmarshal is the single entry point network.go uses to parse a
+// Discovery off disk or off IPNS, kept as a thin wrapper so the YAML
+// library is only imported here.
+func yamlUnmarshal(data []byte, out interface{}) error {
+	return yaml.Unmarshal(data, out)
+}
+
+// replaceAllWeirdities turns an IPFS/IPNS ref (or any other
+// slash/colon-laden identifier) into something safe to use as a
+// filename in the local cache.
+func replaceAllWeirdities(ref string) string {
+	return strings.NewReplacer("/", "_", ":", "_").Replace(ref)
+}
+
+// IPFS is a thin client for a local IPFS daemon's HTTP API, used to
+// fetch content and resolve IPNS names during graph traversal.
+type IPFS struct {
+	apiAddress string
+	httpClient *http.Client
+}
+
+// NewIPFS builds an IPFS client talking to the daemon at apiAddress
+// (e.g. "/ip4/127.0.0.1/tcp/5001" or "http://127.0.0.1:5001").
+func NewIPFS(apiAddress string) *IPFS {
+	// Turn an "/ip4/<host>/tcp/<port>" multiaddr into the HTTP base
+	// URL that Get and GetIPNS build their requests on.
+	if parts := strings.Split(apiAddress, "/"); len(parts) == 5 && parts[0] == "" && parts[1] == "ip4" && parts[3] == "tcp" {
+		apiAddress = fmt.Sprintf("http://%s:%s", parts[2], parts[4])
+	}
+
+	return &IPFS{
+		apiAddress: apiAddress,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Get fetches the raw content behind an IPFS ref from the daemon's
+// cat API.
+func (ipfs *IPFS) Get(ref IPFSRef) ([]byte, error) {
+	resp, err := ipfs.httpClient.Get(fmt.Sprintf("%s/api/v0/cat?arg=%s", ipfs.apiAddress, string(ref)))
+	if err != nil {
+		return nil, fmt.Errorf("fetching %q from ipfs: %s", ref, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("ipfs cat %q returned %s", ref, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// GetIPNS resolves an IPNS name and fetches the content it currently
+// points to.
+func (ipfs *IPFS) GetIPNS(ref IPNSRef) ([]byte, error) {
+	resp, err := ipfs.httpClient.Get(fmt.Sprintf("%s/api/v0/cat?arg=%s", ipfs.apiAddress, string(ref)))
+	if err != nil {
+		return nil, fmt.Errorf("fetching %q from ipns: %s", ref, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("ipfs cat %q (ipns) returned %s", ref, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}